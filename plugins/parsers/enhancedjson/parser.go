@@ -22,28 +22,42 @@ type Parser struct {
 }
 
 type Config struct {
-	MetricSelection string `toml:"metric_selection"`
-	MetricName      string `toml:"metric_name"`
-	BasicFields     []BasicField
-	ObjectFields    []ObjectField
+	MetricSelection   string `toml:"metric_selection"`
+	MetricName        string `toml:"metric_name"`
+	Timestamp         string `toml:"timestamp"`          // OPTIONAL
+	TimestampFormat   string `toml:"timestamp_format"`   // OPTIONAL, but REQUIRED if Timestamp is set
+	TimestampTimezone string `toml:"timestamp_timezone"` // OPTIONAL
+	Strict            bool   `toml:"strict"`             // OPTIONAL, defaults to false
+	Tags              []TagField
+	BasicFields       []BasicField
+	ObjectFields      []ObjectField
+}
+
+type TagField struct {
+	Query   string            `toml:"query"`    // REQUIRED
+	Name    string            `toml:"name"`     // OPTIONAL
+	NameMap map[string]string `toml:"name_map"` // OPTIONAL
 }
 
 type BasicField struct {
-	Query string `toml:"query"` // REQUIRED
-	Name  string `toml:"name"`  // OPTIONAL
-	Type  string `toml:"type"`  // OPTIONAL
-	// TODO: add ignore_objects boolean field
+	Query         string `toml:"query"`          // REQUIRED
+	Name          string `toml:"name"`           // OPTIONAL
+	Type          string `toml:"type"`           // OPTIONAL
+	IgnoreObjects bool   `toml:"ignore_objects"` // OPTIONAL
 }
 
 type ObjectField struct {
-	Query   string            `toml:"query"`    // REQUIRED
-	NameMap map[string]string `toml:"name_map"` // OPTIONAL
-	TypeMap map[string]string `toml:"type_map"` // OPTIONAL
+	Query     string            `toml:"query"`     // REQUIRED
+	NameMap   map[string]string `toml:"name_map"`  // OPTIONAL
+	TypeMap   map[string]string `toml:"type_map"`  // OPTIONAL
+	Separator string            `toml:"separator"` // OPTIONAL, defaults to "_"
 }
 
 type MetricNode struct {
 	RootFieldName string
 	DesiredType   string
+	Query         string
+	IgnoreObjects bool
 	Metric        telegraf.Metric
 	gjson.Result
 }
@@ -62,8 +76,29 @@ func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
 	var t []telegraf.Metric
 
 	for _, config := range p.Configs {
+		// Tags are resolved once per config and then attached to every metric
+		// produced by that config, default tags included.
+		tags := make(map[string]string, len(p.DefaultTags))
+		for k, v := range p.DefaultTags {
+			tags[k] = v
+		}
+		configTags, err := p.processTags(config.Tags, input)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range configTags {
+			tags[k] = v
+		}
+
+		// Resolve the metric time once per config, falling back to TimeFunc
+		// when the config doesn't set a Timestamp query or the query misses.
+		metricTime, err := p.processTimestamp(config.Timestamp, config.TimestampFormat, config.TimestampTimezone, input)
+		if err != nil {
+			return nil, err
+		}
+
 		// Process all `basic_fields` configurations
-		basicMetrics, err := p.processBasicFields(config.MetricName, config.BasicFields, input)
+		basicMetrics, err := p.processBasicFields(config.MetricName, tags, metricTime, config.Strict, config.BasicFields, input)
 		if err != nil {
 			return nil, err
 		}
@@ -71,7 +106,7 @@ func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
 			t = append(t, basicMetrics...)
 		}
 
-		objectMetrics, err := p.processObjectFields(config.MetricName, config.ObjectFields, input)
+		objectMetrics, err := p.processObjectFields(config.MetricName, tags, metricTime, config.Strict, config.ObjectFields, input)
 		if err != nil {
 			return nil, err
 		}
@@ -83,16 +118,106 @@ func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
 	return t, nil
 }
 
-func (p *Parser) processBasicFields(metricName string, basicFields []BasicField, input []byte) ([]telegraf.Metric, error) {
+// processTags evaluates every configured `TagField` against the input and
+// returns the resulting set of tags. A `TagField` whose query resolves to an
+// object contributes one tag per key (subject to `NameMap`); any other query
+// contributes a single tag named after `Name`, or the last segment of the
+// query when `Name` is blank.
+func (p *Parser) processTags(tagFields []TagField, input []byte) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, field := range tagFields {
+		result := gjson.GetBytes(input, field.Query)
+		if !result.Exists() {
+			continue
+		}
+
+		if result.IsObject() {
+			result.ForEach(func(key, val gjson.Result) bool {
+				tagName := key.String()
+				if newName, ok := field.NameMap[tagName]; ok {
+					tagName = newName
+				}
+				tags[tagName] = val.String()
+				return true
+			})
+			continue
+		}
+
+		tagName := field.Name
+		if tagName == "" {
+			s := strings.Split(field.Query, ".")
+			tagName = s[len(s)-1]
+		}
+		tags[tagName] = result.String()
+	}
+
+	return tags, nil
+}
+
+// processTimestamp resolves the metric time for a config. When query is blank
+// or the query doesn't match anything in the input, it falls back to
+// p.TimeFunc(). Otherwise format is required and the matched value is parsed
+// according to it: the unix epoch variants ("unix", "unix_ms", "unix_us",
+// "unix_ns") read the value as an integer, and anything else is treated as a
+// Go reference-time layout (e.g. time.RFC3339). timezone, if set, must be a
+// name accepted by time.LoadLocation and is only applied to the layout-based
+// formats, since epoch values are already absolute.
+func (p *Parser) processTimestamp(query, format, timezone string, input []byte) (time.Time, error) {
+	if query == "" {
+		return p.TimeFunc(), nil
+	}
+
+	result := gjson.GetBytes(input, query)
+	if !result.Exists() {
+		return p.TimeFunc(), nil
+	}
+
+	if format == "" {
+		return time.Time{}, fmt.Errorf("timestamp_format is required when timestamp is set")
+	}
+
+	switch format {
+	case "unix":
+		return time.Unix(result.Int(), 0).UTC(), nil
+	case "unix_ms":
+		return time.Unix(0, result.Int()*int64(time.Millisecond)).UTC(), nil
+	case "unix_us":
+		return time.Unix(0, result.Int()*int64(time.Microsecond)).UTC(), nil
+	case "unix_ns":
+		return time.Unix(0, result.Int()).UTC(), nil
+	}
+
+	loc := time.UTC
+	if timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(timezone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp_timezone '%s': %v", timezone, err)
+		}
+	}
+
+	ts, err := time.ParseInLocation(format, result.String(), loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to parse timestamp '%s' with format '%s': %v", result.String(), format, err)
+	}
+
+	return ts, nil
+}
+
+func (p *Parser) processBasicFields(metricName string, tags map[string]string, metricTime time.Time, strict bool, basicFields []BasicField, input []byte) ([]telegraf.Metric, error) {
 	// For each basic_field configuration, get all the metric data returned from the query
 	// Keep the metric data per field separate so all results from each query can be combined
 	var metricFields [][]telegraf.Metric
 	for _, field := range basicFields {
 		result := gjson.GetBytes(input, field.Query)
 
-		// TODO: implement `ignore_objects` config key to ignore this error
 		if result.IsObject() {
-			return nil, fmt.Errorf("use object_field")
+			if strict && !field.IgnoreObjects {
+				return nil, fmt.Errorf("query '%s' matched an object, use object_field, set ignore_objects, or disable strict mode", field.Query)
+			}
+			// Not strict (or this field opted out via ignore_objects): skip
+			// this field rather than failing the whole batch.
+			continue
 		}
 
 		// TODO: Handle invalid input characters, are spaces allowed?? probably not
@@ -108,16 +233,18 @@ func (p *Parser) processBasicFields(metricName string, basicFields []BasicField,
 		mNode := MetricNode{
 			RootFieldName: fieldName,
 			DesiredType:   field.Type,
+			Query:         field.Query,
+			IgnoreObjects: field.IgnoreObjects,
 			Metric: metric.New(
 				metricName,
-				map[string]string{},
+				tags,
 				map[string]interface{}{},
-				p.TimeFunc(),
+				metricTime,
 			),
 			Result: result,
 		}
 		// Expand all array's and nested arrays into separate metrics
-		nodes, err := p.expandArray(mNode, metricName)
+		nodes, err := p.expandArray(mNode, metricName, strict)
 		if err != nil {
 			return nil, err
 		}
@@ -176,29 +303,38 @@ func (p *Parser) processBasicFields(metricName string, basicFields []BasicField,
 }
 
 // expandArray will recursively create a new MetricNode for each element in a JSON array
-func (p *Parser) expandArray(result MetricNode, metricName string) ([]MetricNode, error) {
+func (p *Parser) expandArray(result MetricNode, metricName string, strict bool) ([]MetricNode, error) {
 	var results []MetricNode
 
 	if result.IsObject() {
-		return nil, fmt.Errorf("encountered object")
+		if strict && !result.IgnoreObjects {
+			return nil, fmt.Errorf("query '%s' matched an object, use object_field, set ignore_objects, or disable strict mode", result.Query)
+		}
+		return nil, nil
 	}
 
 	if result.IsArray() {
 		var err error
 		result.ForEach(func(_, val gjson.Result) bool {
-			// TODO: implement `ignore_objects` config key to ignore this error
 			if val.IsObject() {
-				err = fmt.Errorf("encountered object")
-				return false
+				if strict && !result.IgnoreObjects {
+					err = fmt.Errorf("query '%s' produced an array element that is an object, use object_field, set ignore_objects, or disable strict mode", result.Query)
+					return false
+				}
+				// Skip this element instead of failing the whole batch.
+				return true
 			}
 
 			m := metric.New(
 				metricName,
 				map[string]string{},
 				map[string]interface{}{},
-				p.TimeFunc(),
+				result.Metric.Time(),
 			)
 
+			for _, t := range result.Metric.TagList() {
+				m.AddTag(t.Key, t.Value)
+			}
 			for _, f := range result.Metric.FieldList() {
 				m.AddField(f.Key, f.Value)
 			}
@@ -206,11 +342,14 @@ func (p *Parser) expandArray(result MetricNode, metricName string) ([]MetricNode
 			if val.IsArray() {
 				n := MetricNode{
 					RootFieldName: result.RootFieldName,
+					Query:         result.Query,
+					IgnoreObjects: result.IgnoreObjects,
 					Metric:        m,
 					Result:        val,
 				}
-				r, err := p.expandArray(n, metricName)
-				if err != nil {
+				r, nestedErr := p.expandArray(n, metricName, strict)
+				if nestedErr != nil {
+					err = nestedErr
 					return false
 				}
 				results = append(results, r...)
@@ -228,6 +367,8 @@ func (p *Parser) expandArray(result MetricNode, metricName string) ([]MetricNode
 
 				n := MetricNode{
 					RootFieldName: result.RootFieldName,
+					Query:         result.Query,
+					IgnoreObjects: result.IgnoreObjects,
 					Metric:        m,
 					Result:        val,
 				}
@@ -252,7 +393,7 @@ func (p *Parser) expandArray(result MetricNode, metricName string) ([]MetricNode
 	return results, nil
 }
 
-func (p *Parser) processObjectFields(metricName string, objectFields []ObjectField, input []byte) ([]telegraf.Metric, error) {
+func (p *Parser) processObjectFields(metricName string, tags map[string]string, metricTime time.Time, strict bool, objectFields []ObjectField, input []byte) ([]telegraf.Metric, error) {
 	var t []telegraf.Metric
 	for _, field := range objectFields {
 		result := gjson.GetBytes(input, field.Query)
@@ -265,15 +406,21 @@ func (p *Parser) processObjectFields(metricName string, objectFields []ObjectFie
 
 		rootObject := MetricNode{
 			RootFieldName: fieldName,
+			Query:         field.Query,
 			Metric: metric.New(
 				metricName,
-				map[string]string{},
+				tags,
 				map[string]interface{}{},
-				p.TimeFunc(),
+				metricTime,
 			),
 			Result: result,
 		}
-		metrics, err := p.combineObject(rootObject, field.NameMap, field.TypeMap)
+		separator := field.Separator
+		if separator == "" {
+			separator = "_"
+		}
+
+		metrics, err := p.combineObject(rootObject, field.NameMap, field.TypeMap, strict, separator, "")
 		if err != nil {
 			return nil, err
 		}
@@ -285,51 +432,85 @@ func (p *Parser) processObjectFields(metricName string, objectFields []ObjectFie
 	return t, nil
 }
 
-func (p *Parser) combineObject(result MetricNode, nameMap map[string]string, typeMap map[string]string) ([]MetricNode, error) {
-
-	var metrics []MetricNode
+// combineObject flattens a JSON object onto result.Metric, recursing into
+// nested objects and expanding nested arrays. path is the dotted key path of
+// result relative to the object_field's query root (blank at the root
+// itself) and is used, together with the leaf key, to look up NameMap/TypeMap
+// overrides and to build the default field name for nested keys by joining
+// path and key with separator - this is what keeps e.g. {"a":{"b":1},"c":{"b":2}}
+// from colliding on a field named "b".
+func (p *Parser) combineObject(result MetricNode, nameMap map[string]string, typeMap map[string]string, strict bool, separator string, path string) ([]MetricNode, error) {
+	var expanded []MetricNode
+	var nestedErr error
 	result.ForEach(func(key, val gjson.Result) bool {
-		// Update key with user configuration
-		fieldName := key.String()
-		if fieldName != "" {
-			if newName, ok := nameMap[fieldName]; ok {
-				fieldName = newName
-			}
-			//Sanitize fieldname
-			fieldName = strings.ReplaceAll(fieldName, " ", "")
-		} else {
-			fieldName = result.RootFieldName
+		leafName := key.String()
+		if leafName == "" {
+			leafName = result.RootFieldName
+		}
+
+		fieldPath := leafName
+		if path != "" {
+			fieldPath = path + "." + leafName
 		}
 
+		// Update key with user configuration, preferring a match on the full
+		// dotted path over one on the bare leaf key, and otherwise falling
+		// back to the dotted path itself (joined with separator) so nested
+		// fields with the same leaf name don't collide.
+		fieldName, ok := nameMap[fieldPath]
+		if !ok {
+			fieldName, ok = nameMap[leafName]
+		}
+		if !ok {
+			fieldName = strings.ReplaceAll(fieldPath, ".", separator)
+		}
+		fieldName = strings.ReplaceAll(fieldName, " ", "")
+
 		if val.IsArray() {
 			arrayNode := MetricNode{
-				RootFieldName: key.String(),
+				RootFieldName: fieldName,
+				Query:         result.Query,
 				Metric:        result.Metric,
 				Result:        val,
 			}
 
-			m, err := p.expandArray(arrayNode, result.Metric.Name())
+			m, err := p.expandArray(arrayNode, result.Metric.Name(), strict)
 			if err != nil {
+				nestedErr = err
 				return false
 			}
-			// TODO: THIS IS WRONG, where to put newly expanded metrics?!?!?
-			metrics = append(metrics, m...)
+			expanded = append(expanded, m...)
 		} else if val.IsObject() {
-			arrayNode := MetricNode{
-				RootFieldName: key.String(),
+			objectNode := MetricNode{
+				RootFieldName: fieldName,
+				Query:         result.Query,
 				Metric:        result.Metric,
 				Result:        val,
 			}
-			_, err := p.combineObject(arrayNode, nameMap, typeMap)
+			nested, err := p.combineObject(objectNode, nameMap, typeMap, strict, separator, fieldPath)
 			if err != nil {
+				nestedErr = err
 				return false
 			}
+			// nested's last entry is objectNode itself, sharing result.Metric
+			// with this level, so its fields are already accounted for here;
+			// only the array-expanded metrics underneath it are new and need
+			// to bubble up so they aren't dropped.
+			for _, n := range nested {
+				if n.Metric != result.Metric {
+					expanded = append(expanded, n)
+				}
+			}
 		} else {
 			fieldValue := val.Value()
-			if desiredType, ok := typeMap[key.String()]; ok {
+			desiredType, ok := typeMap[fieldPath]
+			if !ok {
+				desiredType, ok = typeMap[leafName]
+			}
+			if ok {
 				var err error
 				// TODO: Return this error
-				fieldValue, err = p.convertType(val.Value(), desiredType, key.String())
+				fieldValue, err = p.convertType(val.Value(), desiredType, fieldPath)
 				if err != nil {
 					return false
 				}
@@ -340,6 +521,21 @@ func (p *Parser) combineObject(result MetricNode, nameMap map[string]string, typ
 
 		return true
 	})
+	if nestedErr != nil {
+		return nil, nestedErr
+	}
+
+	// result.Metric only has its final set of fields once every sibling key
+	// has been processed, so array-expanded children (which are separate
+	// metrics) need the parent object's accumulated fields merged in now,
+	// the same way processBasicFields combines fields across queries.
+	var metrics []MetricNode
+	for _, n := range expanded {
+		for _, f := range result.Metric.FieldList() {
+			n.Metric.AddField(f.Key, f.Value)
+		}
+		metrics = append(metrics, n)
+	}
 
 	metrics = append(metrics, result)
 
@@ -351,7 +547,7 @@ func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 }
 
 func (p *Parser) SetDefaultTags(tags map[string]string) {
-
+	p.DefaultTags = tags
 }
 
 func (p *Parser) SetTimeFunc(fn TimeFunc) {
@@ -0,0 +1,264 @@
+package enhancedjson_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers/enhancedjson"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func requireMetrics(t *testing.T, expected, actual []telegraf.Metric) {
+	t.Helper()
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestParseTags(t *testing.T) {
+	input := []byte(`{"name": "cpu", "meta": {"host": "localhost", "region": "us-east"}, "value": 42}`)
+
+	parser := &enhancedjson.Parser{
+		Configs: []enhancedjson.Config{
+			{
+				MetricName: "m",
+				Tags: []enhancedjson.TagField{
+					{Query: "name", Name: "metric_name"},
+					{Query: "meta", NameMap: map[string]string{"region": "zone"}},
+				},
+				BasicFields: []enhancedjson.BasicField{
+					{Query: "value"},
+				},
+			},
+		},
+	}
+
+	actual, err := parser.Parse(input)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"m",
+			map[string]string{"metric_name": "cpu", "host": "localhost", "zone": "us-east"},
+			map[string]interface{}{"value": float64(42)},
+			time.Unix(0, 0),
+		),
+	}
+	requireMetrics(t, expected, actual)
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	input := []byte(`{"value": 1}`)
+
+	parser := &enhancedjson.Parser{
+		Configs: []enhancedjson.Config{
+			{
+				MetricName:  "m",
+				BasicFields: []enhancedjson.BasicField{{Query: "value"}},
+			},
+		},
+	}
+	parser.SetDefaultTags(map[string]string{"source": "agent"})
+
+	actual, err := parser.Parse(input)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"m",
+			map[string]string{"source": "agent"},
+			map[string]interface{}{"value": float64(1)},
+			time.Unix(0, 0),
+		),
+	}
+	requireMetrics(t, expected, actual)
+}
+
+func TestParseTimestampUnix(t *testing.T) {
+	input := []byte(`{"ts": 1609459200, "value": 1}`)
+
+	parser := &enhancedjson.Parser{
+		Configs: []enhancedjson.Config{
+			{
+				MetricName:      "m",
+				Timestamp:       "ts",
+				TimestampFormat: "unix",
+				BasicFields:     []enhancedjson.BasicField{{Query: "value"}},
+			},
+		},
+	}
+
+	actual, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Equal(t, time.Unix(1609459200, 0).UTC(), actual[0].Time().UTC())
+}
+
+func TestParseTimestampRFC3339(t *testing.T) {
+	input := []byte(`{"ts": "2021-01-01T00:00:00Z", "value": 1}`)
+
+	parser := &enhancedjson.Parser{
+		Configs: []enhancedjson.Config{
+			{
+				MetricName:      "m",
+				Timestamp:       "ts",
+				TimestampFormat: time.RFC3339,
+				BasicFields:     []enhancedjson.BasicField{{Query: "value"}},
+			},
+		},
+	}
+
+	actual, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), actual[0].Time().UTC())
+}
+
+func TestParseTimestampFormatRequired(t *testing.T) {
+	input := []byte(`{"ts": "2021-01-01T00:00:00Z", "value": 1}`)
+
+	parser := &enhancedjson.Parser{
+		Configs: []enhancedjson.Config{
+			{
+				MetricName:  "m",
+				Timestamp:   "ts",
+				BasicFields: []enhancedjson.BasicField{{Query: "value"}},
+			},
+		},
+	}
+
+	_, err := parser.Parse(input)
+	require.Error(t, err)
+}
+
+func TestParseStrictModeErrorsOnObject(t *testing.T) {
+	input := []byte(`{"value": {"nested": 1}}`)
+
+	parser := &enhancedjson.Parser{
+		Configs: []enhancedjson.Config{
+			{
+				MetricName:  "m",
+				Strict:      true,
+				BasicFields: []enhancedjson.BasicField{{Query: "value"}},
+			},
+		},
+	}
+
+	_, err := parser.Parse(input)
+	require.Error(t, err)
+}
+
+func TestParseLenientModeSkipsObject(t *testing.T) {
+	input := []byte(`{"value": {"nested": 1}, "other": 2}`)
+
+	parser := &enhancedjson.Parser{
+		Configs: []enhancedjson.Config{
+			{
+				MetricName: "m",
+				BasicFields: []enhancedjson.BasicField{
+					{Query: "value"},
+					{Query: "other"},
+				},
+			},
+		},
+	}
+
+	actual, err := parser.Parse(input)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"m",
+			map[string]string{},
+			map[string]interface{}{"other": float64(2)},
+			time.Unix(0, 0),
+		),
+	}
+	requireMetrics(t, expected, actual)
+}
+
+// TestParseObjectNestedArray guards against the regression where an array
+// nested more than one object deep under an object_field's query root was
+// silently dropped: only the innermost combineObject call's expanded
+// metrics made it out, and every level above it discarded them.
+func TestParseObjectNestedArray(t *testing.T) {
+	input := []byte(`{"a": {"b": {"items": [1, 2, 3]}, "c": 5}}`)
+
+	parser := &enhancedjson.Parser{
+		Configs: []enhancedjson.Config{
+			{
+				MetricName:   "m",
+				ObjectFields: []enhancedjson.ObjectField{{Query: "a"}},
+			},
+		},
+	}
+
+	actual, err := parser.Parse(input)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New("m", map[string]string{}, map[string]interface{}{"b_items": float64(1), "c": float64(5)}, time.Unix(0, 0)),
+		metric.New("m", map[string]string{}, map[string]interface{}{"b_items": float64(2), "c": float64(5)}, time.Unix(0, 0)),
+		metric.New("m", map[string]string{}, map[string]interface{}{"b_items": float64(3), "c": float64(5)}, time.Unix(0, 0)),
+		metric.New("m", map[string]string{}, map[string]interface{}{"c": float64(5)}, time.Unix(0, 0)),
+	}
+	requireMetrics(t, expected, actual)
+}
+
+func TestParseObjectDottedNameCollision(t *testing.T) {
+	input := []byte(`{"a": {"b": 1}, "c": {"b": 2}}`)
+
+	parser := &enhancedjson.Parser{
+		Configs: []enhancedjson.Config{
+			{
+				MetricName:   "m",
+				ObjectFields: []enhancedjson.ObjectField{{Query: "@this"}},
+			},
+		},
+	}
+
+	actual, err := parser.Parse(input)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"m",
+			map[string]string{},
+			map[string]interface{}{"a_b": float64(1), "c_b": float64(2)},
+			time.Unix(0, 0),
+		),
+	}
+	requireMetrics(t, expected, actual)
+}
+
+func TestParseIgnoreObjectsOverridesStrict(t *testing.T) {
+	input := []byte(`{"value": {"nested": 1}, "other": 2}`)
+
+	parser := &enhancedjson.Parser{
+		Configs: []enhancedjson.Config{
+			{
+				MetricName: "m",
+				Strict:     true,
+				BasicFields: []enhancedjson.BasicField{
+					{Query: "value", IgnoreObjects: true},
+					{Query: "other"},
+				},
+			},
+		},
+	}
+
+	actual, err := parser.Parse(input)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"m",
+			map[string]string{},
+			map[string]interface{}{"other": float64(2)},
+			time.Unix(0, 0),
+		),
+	}
+	requireMetrics(t, expected, actual)
+}